@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/youlab/youlab-sync/internal/ignore"
+	"github.com/youlab/youlab-sync/internal/ralph"
+	"github.com/youlab/youlab-sync/internal/sync"
+)
+
+var conflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "List and resolve outstanding sync conflicts",
+	Long: `List files that were found to have changed on both the local and
+remote sides since the last sync, and interactively resolve them by
+choosing which side should win.`,
+	RunE: runConflicts,
+}
+
+func init() {
+	rootCmd.AddCommand(conflictsCmd)
+}
+
+func runConflicts(cmd *cobra.Command, args []string) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	client := ralph.NewClient(cfg.Server.URL, cfg.Server.APIKey, cfg.Server.UserID)
+	matcher := ignore.NewMatcher(cfg.Ignore)
+	syncManager, err := sync.NewManager(client, cfg.Sync.LocalFolder, matcher, cfg.Sync.ChunkSize, cfg.Sync.ChunkUploadThreshold, cfg.Sync.ConflictStrategy, cfg.Sync.StagingDir, cfg.Sync.Hashers)
+	if err != nil {
+		return err
+	}
+	defer syncManager.Close()
+
+	conflicts := syncManager.ListConflicts()
+	if len(conflicts) == 0 {
+		fmt.Println("No outstanding conflicts.")
+		return nil
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Path < conflicts[j].Path
+	})
+
+	fmt.Printf("%d outstanding conflict(s):\n\n", len(conflicts))
+
+	ctx := context.Background()
+	reader := bufio.NewReader(os.Stdin)
+
+	for _, c := range conflicts {
+		fmt.Printf("- %s (resolved by %s at %s)\n", c.Path, c.Strategy, c.DetectedAt.Format("2006-01-02 15:04:05"))
+		if c.ConflictPath != "" {
+			fmt.Printf("  losing version kept as: %s\n", c.ConflictPath)
+		}
+		fmt.Print("  Keep [l]ocal, [r]emote, or [s]kip? ")
+
+		line, _ := reader.ReadString('\n')
+		choice := strings.TrimSpace(strings.ToLower(line))
+
+		switch choice {
+		case "l", "local":
+			if err := syncManager.ResolveConflict(ctx, c.Path, "local"); err != nil {
+				fmt.Printf("  failed: %v\n", err)
+			} else {
+				fmt.Println("  resolved (local kept)")
+			}
+		case "r", "remote":
+			if err := syncManager.ResolveConflict(ctx, c.Path, "remote"); err != nil {
+				fmt.Printf("  failed: %v\n", err)
+			} else {
+				fmt.Println("  resolved (remote kept)")
+			}
+		default:
+			fmt.Println("  skipped")
+		}
+	}
+
+	return nil
+}
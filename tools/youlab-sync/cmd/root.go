@@ -8,12 +8,14 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/youlab/youlab-sync/internal/config"
+	"github.com/youlab/youlab-sync/internal/logging"
 )
 
 var (
-	cfgFile string
-	cfg     *config.Config
-	verbose bool
+	cfgFile            string
+	cfg                *config.Config
+	verbose            bool
+	conflictResolution string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -40,6 +42,11 @@ to the Ralph server, while also pulling down changes made by the AI agent.`,
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
+		// --conflict-resolution overrides whatever the config file says
+		if conflictResolution != "" {
+			cfg.Sync.ConflictStrategy = conflictResolution
+		}
+
 		// Override log level from config if not verbose
 		if !verbose && cfg.Logging.Level != "" {
 			level, err := logrus.ParseLevel(cfg.Logging.Level)
@@ -48,6 +55,14 @@ to the Ralph server, while also pulling down changes made by the AI agent.`,
 			}
 		}
 
+		// logging.Debug/Debugf log through logrus, so a facility enabled via
+		// YOULAB_TRACE still produces nothing unless logrus's own level is
+		// at least Debug. Raise it rather than requiring --verbose too, so
+		// tracing one subsystem doesn't force every other log line on.
+		if logging.AnyEnabled() && logrus.GetLevel() < logrus.DebugLevel {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+
 		return nil
 	},
 }
@@ -70,4 +85,5 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", defaultConfig, "config file")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&conflictResolution, "conflict-resolution", "", "conflict resolution policy: newer, local, remote, or manual (overrides config)")
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/youlab/youlab-sync/internal/ignore"
 	"github.com/youlab/youlab-sync/internal/ralph"
 	"github.com/youlab/youlab-sync/internal/sync"
 )
@@ -38,15 +39,25 @@ func runSync(cmd *cobra.Command, args []string) error {
 	client := ralph.NewClient(cfg.Server.URL, cfg.Server.APIKey, cfg.Server.UserID)
 
 	// Create sync manager
-	syncManager, err := sync.NewManager(client, cfg.Sync.LocalFolder, cfg.Ignore)
+	matcher := ignore.NewMatcher(cfg.Ignore)
+	syncManager, err := sync.NewManager(client, cfg.Sync.LocalFolder, matcher, cfg.Sync.ChunkSize, cfg.Sync.ChunkUploadThreshold, cfg.Sync.ConflictStrategy, cfg.Sync.StagingDir, cfg.Sync.Hashers)
 	if err != nil {
 		return err
 	}
+	defer syncManager.Close()
 
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
+	if err := syncManager.Reconcile(ctx); err != nil {
+		logrus.Errorf("Failed to reconcile sync state: %v", err)
+	}
+
+	if err := syncManager.ResumeUploads(ctx); err != nil {
+		logrus.Errorf("Failed to resume uploads: %v", err)
+	}
+
 	// Perform sync
 	if err := syncManager.FullSync(ctx); err != nil {
 		return err
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/youlab/youlab-sync/internal/ignore"
 	"github.com/youlab/youlab-sync/internal/ralph"
 	"github.com/youlab/youlab-sync/internal/sync"
 	"github.com/youlab/youlab-sync/internal/watcher"
@@ -45,11 +47,42 @@ func runWatch(cmd *cobra.Command, args []string) error {
 	// Create Ralph client
 	client := ralph.NewClient(cfg.Server.URL, cfg.Server.APIKey, cfg.Server.UserID)
 
+	// Apply retry policy for chunked uploads
+	client.SetRetryPolicy(ralph.RetryPolicy{
+		BaseDelay:   cfg.Sync.Retry.BaseDelay,
+		MaxDelay:    cfg.Sync.Retry.MaxDelay,
+		Jitter:      cfg.Sync.Retry.Jitter,
+		MaxAttempts: cfg.Sync.Retry.MaxAttempts,
+	})
+
+	// Cap throughput for users on metered connections
+	client.SetRateLimits(cfg.Sync.MaxUploadBytesPerSec, cfg.Sync.MaxDownloadBytesPerSec)
+
+	// Wire up bandwidth accounting and, if enabled, serve it for Prometheus
+	metrics := ralph.NewMetrics()
+	client.SetMetrics(metrics)
+	if cfg.Metrics.Enabled {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go func() {
+			logrus.Infof("Metrics server listening on %s", cfg.Metrics.Addr)
+			if err := http.ListenAndServe(cfg.Metrics.Addr, mux); err != nil {
+				logrus.Errorf("Metrics server failed: %v", err)
+			}
+		}()
+	}
+
+	// A single matcher is shared by the sync manager and the file watcher
+	// so ignore behavior is consistent across the initial walk, live
+	// events, and remote diffing.
+	matcher := ignore.NewMatcher(cfg.Ignore)
+
 	// Create sync manager
-	syncManager, err := sync.NewManager(client, cfg.Sync.LocalFolder, cfg.Ignore)
+	syncManager, err := sync.NewManager(client, cfg.Sync.LocalFolder, matcher, cfg.Sync.ChunkSize, cfg.Sync.ChunkUploadThreshold, cfg.Sync.ConflictStrategy, cfg.Sync.StagingDir, cfg.Sync.Hashers)
 	if err != nil {
 		return err
 	}
+	defer syncManager.Close()
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -59,6 +92,59 @@ func runWatch(cmd *cobra.Command, args []string) error {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	// Reconcile the crash journal before touching anything else, so a kill
+	// mid-upload or mid-download is resolved from last run's state rather
+	// than looking like a fresh divergence.
+	if err := syncManager.Reconcile(ctx); err != nil {
+		logrus.Errorf("Failed to reconcile sync state: %v", err)
+	}
+
+	// Resume any chunked uploads left in-progress by a previous run
+	if err := syncManager.ResumeUploads(ctx); err != nil {
+		logrus.Errorf("Failed to resume uploads: %v", err)
+	}
+
+	// Log conflicts as the sync manager resolves them
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case conflict, ok := <-syncManager.Conflicts():
+				if !ok {
+					return
+				}
+				logrus.Warnf("Conflict on %s resolved by %s policy", conflict.Path, conflict.Strategy)
+			}
+		}
+	}()
+
+	// Prefer server-push notifications over polling for remote changes. If
+	// the server doesn't support the event stream, fall back to relying on
+	// the periodic full sync below.
+	remoteEvents, err := client.Subscribe(ctx)
+	if err != nil {
+		logrus.Warnf("Remote change notifications unavailable, falling back to polling: %v", err)
+	} else {
+		logrus.Info("Subscribed to remote change notifications")
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event, ok := <-remoteEvents:
+					if !ok {
+						return
+					}
+					logrus.Debugf("Remote change detected: %s (%s)", event.Path, event.Op)
+					if err := syncManager.HandleRemoteChange(ctx, event); err != nil {
+						logrus.Errorf("Failed to handle remote change: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
 	// Perform initial sync
 	logrus.Info("Performing initial sync...")
 	if err := syncManager.FullSync(ctx); err != nil {
@@ -69,7 +155,7 @@ func runWatch(cmd *cobra.Command, args []string) error {
 	// Start file watcher if enabled
 	var fileWatcher *watcher.Watcher
 	if cfg.Watch.Enabled {
-		fileWatcher, err = watcher.New(cfg.Sync.LocalFolder, cfg.Ignore, cfg.Watch.Debounce)
+		fileWatcher, err = watcher.New(cfg.Sync.LocalFolder, matcher, cfg.Watch.Debounce)
 		if err != nil {
 			return err
 		}
@@ -105,10 +191,19 @@ func runWatch(cmd *cobra.Command, args []string) error {
 		}()
 	}
 
-	// Start periodic sync for bidirectional mode
+	// Start periodic sync for bidirectional mode. When remote push
+	// notifications are active, this no longer drives day-to-day syncing -
+	// it's demoted to an infrequent reconciliation safety net in case an
+	// event was ever missed.
+	syncInterval := cfg.Sync.Interval
+	if remoteEvents != nil && syncInterval > 0 {
+		syncInterval *= 10
+		logrus.Infof("Push notifications active; demoting periodic full sync to a %s reconciliation safety net", syncInterval)
+	}
+
 	var syncTicker *time.Ticker
-	if cfg.Sync.Bidirectional && cfg.Sync.Interval > 0 {
-		syncTicker = time.NewTicker(cfg.Sync.Interval)
+	if cfg.Sync.Bidirectional && syncInterval > 0 {
+		syncTicker = time.NewTicker(syncInterval)
 		defer syncTicker.Stop()
 
 		go func() {
@@ -125,7 +220,7 @@ func runWatch(cmd *cobra.Command, args []string) error {
 			}
 		}()
 
-		logrus.Infof("Periodic sync enabled (interval: %s)", cfg.Sync.Interval)
+		logrus.Infof("Periodic sync enabled (interval: %s)", syncInterval)
 	}
 
 	logrus.Info("Daemon ready. Press Ctrl+C to stop.")
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -15,6 +16,14 @@ type Config struct {
 	Watch   WatchConfig   `yaml:"watch"`
 	Ignore  []string      `yaml:"ignore"`
 	Logging LoggingConfig `yaml:"logging"`
+	Metrics MetricsConfig `yaml:"metrics"`
+}
+
+// MetricsConfig controls the Prometheus-compatible metrics HTTP server
+// that exposes bandwidth accounting for the Ralph client.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
 }
 
 // ServerConfig defines Ralph server connection settings
@@ -29,6 +38,47 @@ type SyncConfig struct {
 	LocalFolder   string        `yaml:"local_folder"`
 	Interval      time.Duration `yaml:"interval"`
 	Bidirectional bool          `yaml:"bidirectional"`
+	// ChunkSize is the size of each chunk sent to ralph.Client.PutFileChunked
+	// once a file is large enough to go through the chunked-upload path (see
+	// ChunkUploadThreshold).
+	ChunkSize              int64       `yaml:"chunk_size"`
+	Retry                  RetryConfig `yaml:"retry"`
+	MaxUploadBytesPerSec   int64       `yaml:"max_upload_bytes_per_sec"`
+	MaxDownloadBytesPerSec int64       `yaml:"max_download_bytes_per_sec"`
+	// ChunkUploadThreshold is the file size above which uploadFile switches
+	// from a single PutFile call to the resumable chunked-upload path.
+	// Both paths read the whole file into memory first; the chunked path
+	// splits only the transfer, so an interrupted upload can resume from
+	// the server's last confirmed offset.
+	ChunkUploadThreshold int64 `yaml:"chunk_upload_threshold"`
+	// ConflictStrategy controls how Manager resolves a file changed on
+	// both sides since the last sync: "newer" (default; whichever side was
+	// modified most recently wins), "local", "remote", or "manual". manual
+	// never overwrites either side silently: the losing version is always
+	// preserved as a path.sync-conflict-YYYYMMDD-HHMMSS-<shorthash>.ext
+	// sibling for the user to review.
+	ConflictStrategy string `yaml:"conflict_strategy"`
+	// Hashers is the number of worker goroutines scanLocalFiles uses to
+	// hash files concurrently. Defaults to runtime.NumCPU() on Linux and 1
+	// elsewhere (see defaultHashers), and can be overridden with the
+	// YOULAB_HASHERS environment variable.
+	Hashers int `yaml:"hashers"`
+	// StagingDir is where downloaded content is written and fsync'd
+	// before being renamed into place, so a killed process or a full disk
+	// never leaves a half-written file at the destination path. Empty
+	// means "<local_folder>/.youlab-sync/tmp". It should stay on the same
+	// filesystem as LocalFolder, since the atomicity of the final step
+	// depends on os.Rename being a same-filesystem rename.
+	StagingDir string `yaml:"staging_dir"`
+}
+
+// RetryConfig defines the exponential backoff policy used when retrying
+// failed chunk uploads and other transient request failures.
+type RetryConfig struct {
+	BaseDelay   time.Duration `yaml:"base_delay"`
+	MaxDelay    time.Duration `yaml:"max_delay"`
+	Jitter      time.Duration `yaml:"jitter"`
+	MaxAttempts int           `yaml:"max_attempts"`
 }
 
 // WatchConfig defines file watching settings
@@ -52,9 +102,19 @@ func DefaultConfig() *Config {
 			UserID: getEnv("YOULAB_USER_ID", ""),
 		},
 		Sync: SyncConfig{
-			LocalFolder:   getEnv("YOULAB_LOCAL_FOLDER", ""),
-			Interval:      30 * time.Second,
-			Bidirectional: true,
+			LocalFolder:          getEnv("YOULAB_LOCAL_FOLDER", ""),
+			Interval:             30 * time.Second,
+			Bidirectional:        true,
+			ChunkSize:            4 * 1024 * 1024,
+			ChunkUploadThreshold: 8 * 1024 * 1024,
+			Retry: RetryConfig{
+				BaseDelay:   500 * time.Millisecond,
+				MaxDelay:    30 * time.Second,
+				Jitter:      250 * time.Millisecond,
+				MaxAttempts: 5,
+			},
+			ConflictStrategy: "newer",
+			Hashers:          getEnvInt("YOULAB_HASHERS", defaultHashers()),
 		},
 		Watch: WatchConfig{
 			Enabled:  true,
@@ -78,6 +138,10 @@ func DefaultConfig() *Config {
 			Level: "info",
 			File:  "",
 		},
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Addr:    ":9090",
+		},
 	}
 }
 
@@ -110,6 +174,9 @@ func Load(path string) (*Config, error) {
 	if folder := os.Getenv("YOULAB_LOCAL_FOLDER"); folder != "" {
 		cfg.Sync.LocalFolder = folder
 	}
+	if hashers := os.Getenv("YOULAB_HASHERS"); hashers != "" {
+		cfg.Sync.Hashers = getEnvInt("YOULAB_HASHERS", cfg.Sync.Hashers)
+	}
 
 	return cfg, nil
 }
@@ -154,3 +221,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,12 @@
+//go:build linux
+
+package config
+
+import "runtime"
+
+// defaultHashers returns the default size of the parallel hashing worker
+// pool. Linux desktops and servers are the common case for running this
+// daemon unattended, so it's safe to use every core.
+func defaultHashers() int {
+	return runtime.NumCPU()
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package config
+
+// defaultHashers returns the default size of the parallel hashing worker
+// pool. On darwin, windows, and android this machine is more likely to be
+// someone's interactive desktop, so default to a single hasher rather than
+// hogging every core the way the Linux default does.
+func defaultHashers() int {
+	return 1
+}
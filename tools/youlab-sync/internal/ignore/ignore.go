@@ -0,0 +1,176 @@
+// Package ignore implements gitignore-compatible pattern matching for
+// youlab-sync. It replaces the basename-only filepath.Match heuristic that
+// used to be duplicated between internal/watcher and internal/sync, adding
+// support for negation, anchored patterns, directory-only patterns, "**",
+// and hierarchical .youlabignore files discovered while walking a tree.
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// rule is a single compiled gitignore-style pattern, scoped to the
+// directory (relative to the matcher's root, "" for the root itself) that
+// defined it.
+type rule struct {
+	scope   string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// Matcher evaluates relative paths against a set of gitignore-style rules.
+// Rules loaded from a nested .youlabignore file are scoped to that
+// directory's subtree; within the paths they cover, later-loaded rules
+// take precedence over earlier ones, the same way a later line in a single
+// .gitignore file overrides an earlier one.
+type Matcher struct {
+	mu    sync.RWMutex
+	rules []rule
+}
+
+// NewMatcher creates a Matcher seeded with root-level patterns, such as
+// config.Config.Ignore, as if they were defined in a .youlabignore file at
+// the root of the tree.
+func NewMatcher(rootPatterns []string) *Matcher {
+	m := &Matcher{}
+	m.add("", rootPatterns)
+	return m
+}
+
+// LoadDir reads the .youlabignore file (if any) from dir, the absolute
+// path of a directory being walked, and scopes its rules to relDir, that
+// directory's path relative to the tree root ("" for the root itself). It
+// is a no-op if the directory has no .youlabignore file.
+func (m *Matcher) LoadDir(dir, relDir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, ".youlabignore"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m.add(relDir, strings.Split(string(data), "\n"))
+	return nil
+}
+
+// Match reports whether relPath (slash-separated, relative to the tree
+// root) should be ignored. isDir indicates whether relPath is a directory,
+// since directory-only patterns ("foo/") only ever match directories.
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ignored := false
+	for _, r := range m.rules {
+		candidate := relPath
+		if r.scope != "" {
+			if relPath != r.scope && !strings.HasPrefix(relPath, r.scope+"/") {
+				continue
+			}
+			candidate = strings.TrimPrefix(relPath, r.scope+"/")
+		}
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(candidate) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func (m *Matcher) add(scope string, lines []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, line := range lines {
+		if r, ok := parseLine(scope, line); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+}
+
+// parseLine compiles a single .gitignore-style pattern line into a rule
+// scoped to scope. It returns ok=false for blank lines and comments.
+func parseLine(scope, line string) (rule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	negate := false
+	if strings.HasPrefix(line, "!") {
+		negate = true
+		line = line[1:]
+	}
+	if strings.HasPrefix(line, "\\") {
+		line = line[1:]
+	}
+
+	dirOnly := strings.HasSuffix(line, "/")
+	if dirOnly {
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// A pattern containing a slash anywhere but the end is anchored to the
+	// directory that defined it; one without a slash may match at any
+	// depth within that directory's subtree.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	re, err := compilePattern(line, anchored)
+	if err != nil {
+		return rule{}, false
+	}
+
+	return rule{scope: scope, negate: negate, dirOnly: dirOnly, re: re}, true
+}
+
+// compilePattern translates a single gitignore glob into a regexp anchored
+// to the full candidate path (relative to the rule's scope).
+func compilePattern(pat string, anchored bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	runes := []rune(pat)
+	for i := 0; i < len(runes); i++ {
+		rest := string(runes[i:])
+		switch {
+		case strings.HasPrefix(rest, "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(rest, "/**"):
+			sb.WriteString("(?:/.*)?")
+			i += 2
+		case runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			sb.WriteString(".*")
+			i++
+		case runes[i] == '*':
+			sb.WriteString("[^/]*")
+		case runes[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+
+	// A pattern also matches anything inside it, so directory patterns
+	// exclude their contents without every entry needing its own rule.
+	sb.WriteString("(?:/.*)?$")
+
+	return regexp.Compile(sb.String())
+}
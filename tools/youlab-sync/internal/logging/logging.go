@@ -0,0 +1,90 @@
+// Package logging adds Syncthing-style STTRACE facility filtering on top of
+// logrus: --verbose alone turns on every Debugf call in the process, which
+// floods the log on anything but a toy workspace. YOULAB_TRACE lets a user
+// enable debug output for just the subsystem they're chasing.
+package logging
+
+import (
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Facility names recognized by YOULAB_TRACE.
+const (
+	Sync     = "sync"
+	Watcher  = "watcher"
+	Ralph    = "ralph"
+	Index    = "index"
+	Conflict = "conflict"
+)
+
+var enabled map[string]bool
+
+func init() {
+	enabled = parse(os.Getenv("YOULAB_TRACE"))
+}
+
+func parse(trace string) map[string]bool {
+	facilities := map[string]bool{
+		Sync: false, Watcher: false, Ralph: false, Index: false, Conflict: false,
+	}
+
+	trace = strings.TrimSpace(trace)
+	if trace == "" {
+		return facilities
+	}
+
+	for _, f := range strings.Split(trace, ",") {
+		f = strings.TrimSpace(strings.ToLower(f))
+		if f == "" {
+			continue
+		}
+		if f == "all" {
+			for k := range facilities {
+				facilities[k] = true
+			}
+			continue
+		}
+		facilities[f] = true
+	}
+
+	return facilities
+}
+
+// Enabled reports whether YOULAB_TRACE turned on the given facility.
+func Enabled(facility string) bool {
+	return enabled[facility]
+}
+
+// AnyEnabled reports whether YOULAB_TRACE turned on at least one facility.
+// cmd/root.go uses this to raise logrus's level to Debug when tracing is
+// requested, since Debug/Debugf log through logrus and are themselves
+// no-ops if the configured level is below Debug regardless of facility.
+func AnyEnabled() bool {
+	for _, v := range enabled {
+		if v {
+			return true
+		}
+	}
+	return false
+}
+
+// Debug logs args at debug level, but only if facility is enabled via
+// YOULAB_TRACE. The enabled check is a cheap map lookup, so call sites on
+// hot paths can call this unconditionally rather than guarding it.
+func Debug(facility string, args ...interface{}) {
+	if !enabled[facility] {
+		return
+	}
+	logrus.Debug(args...)
+}
+
+// Debugf is Debug with Printf-style formatting.
+func Debugf(facility, format string, args ...interface{}) {
+	if !enabled[facility] {
+		return
+	}
+	logrus.Debugf(format, args...)
+}
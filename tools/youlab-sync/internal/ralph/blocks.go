@@ -0,0 +1,217 @@
+package ralph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrBlocksUnsupported indicates the server doesn't implement the
+// block-storage API, so the caller should fall back to whole-file
+// transfer via PutFile/GetFile.
+var ErrBlocksUnsupported = errors.New("ralph: server does not support block storage")
+
+// BlockRef identifies one block of a file's content by its SHA-256 hash,
+// offset, and length, as sent to and received from the block-storage API.
+type BlockRef struct {
+	Hash   string `json:"hash"`
+	Offset int64  `json:"offset"`
+	Length int    `json:"length"`
+}
+
+func blocksUnsupported(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusNotImplemented
+}
+
+// HasBlocks asks the server which of the given hashes it already has
+// stored, so PutFileManifest callers only need to upload the blocks it's
+// missing.
+func (c *Client) HasBlocks(ctx context.Context, hashes []string) (map[string]bool, error) {
+	endpoint := fmt.Sprintf("%s/blocks/query", c.baseURL)
+
+	body, err := json.Marshal(struct {
+		Hashes []string `json:"hashes"`
+	}{Hashes: hashes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal block query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if blocksUnsupported(resp.StatusCode) {
+		return nil, ErrBlocksUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Have map[string]bool `json:"have"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Have, nil
+}
+
+// PutBlock uploads a single content-addressed block. The server stores it
+// keyed by hash, so any other file containing an identical block never
+// needs to send it again.
+func (c *Client) PutBlock(ctx context.Context, hash string, data []byte) error {
+	endpoint := fmt.Sprintf("%s/blocks/%s", c.baseURL, hash)
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	if err := c.uploadLimiter.wait(ctx, len(data)); err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload block: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if blocksUnsupported(resp.StatusCode) {
+		return ErrBlocksUnsupported
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// GetBlock downloads a single content-addressed block.
+func (c *Client) GetBlock(ctx context.Context, hash string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/blocks/%s", c.baseURL, hash)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download block: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if blocksUnsupported(resp.StatusCode) {
+		return nil, ErrBlocksUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	data, err := io.ReadAll(c.downloadLimiter.throttle(ctx, resp.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return data, nil
+}
+
+// PutFileManifest tells the server to assemble relPath from the given
+// blocks, which must all have already been uploaded via PutBlock.
+func (c *Client) PutFileManifest(ctx context.Context, relPath string, blocks []BlockRef) (*FileMetadata, error) {
+	endpoint := fmt.Sprintf("%s/users/%s/workspace/files/%s/manifest", c.baseURL, c.userID, url.PathEscape(relPath))
+
+	body, err := json.Marshal(struct {
+		Blocks []BlockRef `json:"blocks"`
+	}{Blocks: blocks})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if blocksUnsupported(resp.StatusCode) {
+		return nil, ErrBlocksUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var metadata FileMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &metadata, nil
+}
+
+// GetFileManifest fetches the remote block list for relPath without
+// transferring block content, so the caller can diff it against a local
+// block list before downloading anything.
+func (c *Client) GetFileManifest(ctx context.Context, relPath string) ([]BlockRef, *FileMetadata, error) {
+	endpoint := fmt.Sprintf("%s/users/%s/workspace/files/%s/manifest", c.baseURL, c.userID, url.PathEscape(relPath))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if blocksUnsupported(resp.StatusCode) {
+		return nil, nil, ErrBlocksUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Blocks   []BlockRef   `json:"blocks"`
+		Metadata FileMetadata `json:"metadata"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Blocks, &result.Metadata, nil
+}
@@ -3,20 +3,64 @@ package ralph
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/youlab/youlab-sync/internal/logging"
 )
 
+// defaultChunkSize is used by PutFileChunked when the caller doesn't
+// specify one.
+const defaultChunkSize = 4 * 1024 * 1024
+
 // Client provides methods to interact with the Ralph workspace API
 type Client struct {
-	baseURL    string
-	apiKey     string
-	userID     string
-	httpClient *http.Client
+	baseURL     string
+	apiKey      string
+	userID      string
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+
+	metrics         *Metrics
+	uploadLimiter   *tokenBucket
+	downloadLimiter *tokenBucket
+}
+
+// RetryPolicy controls the exponential backoff used when retrying failed
+// chunk uploads: delay = min(BaseDelay*2^attempt, MaxDelay) + rand(Jitter).
+type RetryPolicy struct {
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      time.Duration
+	MaxAttempts int
+}
+
+// DefaultRetryPolicy returns the retry policy used when a client has not
+// been given one explicitly.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      250 * time.Millisecond,
+		MaxAttempts: 5,
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay * time.Duration(int64(1)<<uint(attempt))
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	return delay
 }
 
 // FileMetadata represents metadata for a file in the workspace
@@ -51,9 +95,39 @@ func NewClient(baseURL, apiKey, userID string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retryPolicy: DefaultRetryPolicy(),
 	}
 }
 
+// UserID returns the workspace user ID the client was configured with.
+func (c *Client) UserID() string {
+	return c.userID
+}
+
+// SetRetryPolicy overrides the backoff policy used for chunk upload retries.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retryPolicy = p
+}
+
+// SetMetrics attaches a Metrics collector to the client, wrapping its HTTP
+// transport so every request/response is accounted for at the transport
+// level (including headers and retry traffic, not just payload).
+func (c *Client) SetMetrics(m *Metrics) {
+	c.metrics = m
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.httpClient.Transport = &countingTransport{next: base, metrics: m}
+}
+
+// SetRateLimits caps upload and download throughput to the given bytes per
+// second. A value of 0 means unlimited.
+func (c *Client) SetRateLimits(uploadBytesPerSec, downloadBytesPerSec int64) {
+	c.uploadLimiter = newTokenBucket(uploadBytesPerSec)
+	c.downloadLimiter = newTokenBucket(downloadBytesPerSec)
+}
+
 // ListFiles retrieves the list of files in the user's workspace
 func (c *Client) ListFiles(ctx context.Context) (*WorkspaceIndex, error) {
 	endpoint := fmt.Sprintf("%s/users/%s/workspace/files", c.baseURL, c.userID)
@@ -110,7 +184,7 @@ func (c *Client) GetFile(ctx context.Context, path string) ([]byte, *FileMetadat
 		return nil, nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
 	}
 
-	content, err := io.ReadAll(resp.Body)
+	content, err := io.ReadAll(c.downloadLimiter.throttle(ctx, resp.Body))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -137,6 +211,10 @@ func (c *Client) GetFile(ctx context.Context, path string) ([]byte, *FileMetadat
 
 // PutFile uploads or updates a file in the workspace
 func (c *Client) PutFile(ctx context.Context, path string, content []byte) (*FileMetadata, error) {
+	if err := c.uploadLimiter.wait(ctx, len(content)); err != nil {
+		return nil, err
+	}
+
 	endpoint := fmt.Sprintf("%s/users/%s/workspace/files/%s", c.baseURL, c.userID, url.PathEscape(path))
 
 	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(content))
@@ -166,6 +244,248 @@ func (c *Client) PutFile(ctx context.Context, path string, content []byte) (*Fil
 	return &metadata, nil
 }
 
+// uploadSession is returned by the server when a chunked upload is opened.
+type uploadSession struct {
+	SessionID string `json:"session_id"`
+}
+
+// uploadStatus reports the server's last confirmed offset for a session,
+// so an interrupted upload can resume without resending earlier bytes.
+type uploadStatus struct {
+	ReceivedOffset int64 `json:"received_offset"`
+}
+
+// BeginUpload opens a chunked upload session for path, declaring the total
+// size and SHA-256 hash of the content up front so the server can validate
+// the assembled file in CompleteUpload. It returns the session ID used by
+// PutChunk and CompleteUpload.
+func (c *Client) BeginUpload(ctx context.Context, path string, totalSize int64, totalHash string) (string, error) {
+	endpoint := fmt.Sprintf("%s/users/%s/workspace/files/%s/uploads", c.baseURL, c.userID, url.PathEscape(path))
+
+	body, err := json.Marshal(struct {
+		Size int64  `json:"size"`
+		Hash string `json:"hash"`
+	}{Size: totalSize, Hash: totalHash})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var session uploadSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return session.SessionID, nil
+}
+
+// ResumeUpload queries the server for the last offset it has confirmed
+// receiving for an in-progress upload session, so PutFileChunked can
+// continue from there after a daemon restart instead of resending
+// everything from byte zero.
+func (c *Client) ResumeUpload(ctx context.Context, sessionID string) (int64, error) {
+	endpoint := fmt.Sprintf("%s/uploads/%s", c.baseURL, sessionID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var status uploadStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return status.ReceivedOffset, nil
+}
+
+// PutChunk uploads a single chunk of an in-progress upload session at the
+// given byte offset, retrying on 5xx responses and transport errors using
+// the client's retry policy. chunkHash is the SHA-256 hash of data, sent
+// so the server can reject a chunk that arrived corrupted.
+func (c *Client) PutChunk(ctx context.Context, sessionID string, offset int64, data []byte, chunkHash string) error {
+	if err := c.uploadLimiter.wait(ctx, len(data)); err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/uploads/%s/chunks?offset=%d", c.baseURL, sessionID, offset)
+
+	var lastErr error
+	attempts := c.retryPolicy.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := c.retryPolicy.backoff(attempt - 1)
+			logging.Debugf(logging.Ralph, "Retrying chunk at offset %d (attempt %d/%d) after %v: %v", offset, attempt+1, attempts, delay, lastErr)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		c.setHeaders(req)
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("X-Chunk-Hash", chunkHash)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to execute request: %w", err)
+			continue
+		}
+
+		if resp.StatusCode >= 500 {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		}
+
+		resp.Body.Close()
+		return nil
+	}
+
+	return fmt.Errorf("chunk at offset %d failed after %d attempts: %w", offset, attempts, lastErr)
+}
+
+// CompleteUpload finalizes a chunked upload, asking the server to assemble
+// the received chunks and verify them against totalHash.
+func (c *Client) CompleteUpload(ctx context.Context, sessionID, totalHash string) (*FileMetadata, error) {
+	endpoint := fmt.Sprintf("%s/uploads/%s/complete", c.baseURL, sessionID)
+
+	body := struct {
+		Hash string `json:"hash"`
+	}{Hash: totalHash}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var metadata FileMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &metadata, nil
+}
+
+// PutFileChunked uploads content as a series of chunkSize chunks, each
+// verified with a SHA-256 hash, and resumes from the server's last
+// confirmed offset rather than resending from byte zero. If sessionID is
+// non-empty, it is treated as a session from a previous, interrupted call
+// (as persisted by sync.Manager); ResumeUpload is used to find out how
+// much of it the server already has. It returns the session ID so the
+// caller can persist it until the upload completes, enabling resume
+// across daemon restarts.
+func (c *Client) PutFileChunked(ctx context.Context, path string, content []byte, chunkSize int64, sessionID string) (string, *FileMetadata, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	totalHash := sha256.Sum256(content)
+	totalHashHex := fmt.Sprintf("%x", totalHash)
+
+	var resumeFrom int64
+	if sessionID != "" {
+		if offset, err := c.ResumeUpload(ctx, sessionID); err == nil {
+			resumeFrom = offset
+		} else {
+			// The session the caller persisted is no longer known to the
+			// server (restarted, or its TTL expired) - fall through to
+			// start a fresh one rather than sending chunks against a dead
+			// session forever.
+			sessionID = ""
+		}
+	}
+	if sessionID == "" {
+		sid, err := c.BeginUpload(ctx, path, int64(len(content)), totalHashHex)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to begin upload session: %w", err)
+		}
+		sessionID = sid
+	}
+
+	for offset := resumeFrom; offset < int64(len(content)); offset += chunkSize {
+		end := offset + chunkSize
+		if end > int64(len(content)) {
+			end = int64(len(content))
+		}
+		chunk := content[offset:end]
+		chunkHash := sha256.Sum256(chunk)
+
+		if err := c.PutChunk(ctx, sessionID, offset, chunk, fmt.Sprintf("%x", chunkHash)); err != nil {
+			return sessionID, nil, err
+		}
+	}
+
+	metadata, err := c.CompleteUpload(ctx, sessionID, totalHashHex)
+	if err != nil {
+		return sessionID, nil, err
+	}
+	return sessionID, metadata, nil
+}
+
 // DeleteFile removes a file from the workspace
 func (c *Client) DeleteFile(ctx context.Context, path string) error {
 	endpoint := fmt.Sprintf("%s/users/%s/workspace/files/%s", c.baseURL, c.userID, url.PathEscape(path))
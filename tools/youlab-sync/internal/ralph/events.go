@@ -0,0 +1,174 @@
+package ralph
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/youlab/youlab-sync/internal/logging"
+)
+
+// eventStreamIdleTimeout bounds how long we'll wait between lines on an
+// open event stream before assuming the connection is dead (e.g. a NAT
+// silently dropped it) and reconnecting. The Ralph server is expected to
+// send a comment/ping line at least every 30s to keep such sessions alive.
+const eventStreamIdleTimeout = 45 * time.Second
+
+// RemoteEvent describes a single file change pushed by the server.
+type RemoteEvent struct {
+	Path     string    `json:"path"`
+	Op       string    `json:"op"` // "create", "write", or "remove"
+	Hash     string    `json:"hash"`
+	Modified time.Time `json:"modified"`
+}
+
+// Subscribe opens a long-lived Server-Sent Events stream to
+// /users/{id}/workspace/events and returns a channel of RemoteEvents. If
+// the connection drops, it is automatically reconnected with exponential
+// backoff (the client's RetryPolicy); on reconnect, the last event ID seen
+// is sent as Last-Event-ID so the server can replay anything emitted while
+// disconnected. The returned channel is closed when ctx is canceled.
+func (c *Client) Subscribe(ctx context.Context) (<-chan RemoteEvent, error) {
+	es, err := c.openEventStream(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan RemoteEvent, 100)
+	go c.subscribeLoop(ctx, es, events)
+	return events, nil
+}
+
+// eventStream pairs an in-flight SSE response with the cancel func for the
+// context its request was made with, so an idle timeout can abort the read.
+type eventStream struct {
+	resp   *http.Response
+	cancel context.CancelFunc
+}
+
+func (c *Client) openEventStream(ctx context.Context, lastEventID string) (*eventStream, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	endpoint := fmt.Sprintf("%s/users/%s/workspace/events", c.baseURL, c.userID)
+
+	req, err := http.NewRequestWithContext(streamCtx, "GET", endpoint, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open event stream: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return &eventStream{resp: resp, cancel: cancel}, nil
+}
+
+// subscribeLoop drains es, reconnecting with backoff whenever it ends,
+// until ctx is canceled.
+func (c *Client) subscribeLoop(ctx context.Context, es *eventStream, events chan<- RemoteEvent) {
+	defer close(events)
+
+	lastEventID := ""
+	attempt := 0
+
+	for {
+		if id := c.readEventStream(es, events); id != "" {
+			lastEventID = id
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		for {
+			delay := c.retryPolicy.backoff(attempt)
+			logging.Debugf(logging.Ralph, "Reconnecting event stream (attempt %d) after %v", attempt+1, delay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			attempt++
+
+			var err error
+			es, err = c.openEventStream(ctx, lastEventID)
+			if err == nil {
+				attempt = 0
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// readEventStream reads SSE frames from es until the connection drops or
+// goes idle, decoding each "data:" payload as a RemoteEvent. It returns the
+// last "id:" value seen, if any, for use as a Last-Event-ID on reconnect.
+func (c *Client) readEventStream(es *eventStream, events chan<- RemoteEvent) string {
+	defer es.resp.Body.Close()
+	defer es.cancel()
+
+	idleTimer := time.AfterFunc(eventStreamIdleTimeout, es.cancel)
+	defer idleTimer.Stop()
+
+	scanner := bufio.NewScanner(es.resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var dataLines []string
+	var lastEventID string
+
+	flush := func() {
+		if len(dataLines) == 0 {
+			return
+		}
+		var evt RemoteEvent
+		if err := json.Unmarshal([]byte(strings.Join(dataLines, "\n")), &evt); err == nil {
+			select {
+			case events <- evt:
+			default:
+			}
+		}
+		dataLines = nil
+	}
+
+	for scanner.Scan() {
+		idleTimer.Reset(eventStreamIdleTimeout)
+
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "id:"):
+			lastEventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "data:"):
+			dataLines = append(dataLines, strings.TrimPrefix(line, "data:"))
+		}
+		// Lines starting with ":" are comments, used by the server as a
+		// heartbeat/ping; no action needed beyond resetting idleTimer above.
+	}
+	flush()
+
+	return lastEventID
+}
@@ -0,0 +1,153 @@
+package ralph
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics records transport-level traffic for a Client: raw bytes sent and
+// received (including headers and retry traffic, not just payload) plus
+// request counts by endpoint and status code. It is safe for concurrent use.
+type Metrics struct {
+	bytesSent     uint64
+	bytesReceived uint64
+
+	mu       sync.Mutex
+	requests map[string]map[int]uint64 // endpoint -> status -> count
+}
+
+// NewMetrics creates an empty Metrics collector.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: make(map[string]map[int]uint64),
+	}
+}
+
+func (m *Metrics) recordSent(n uint64) {
+	atomic.AddUint64(&m.bytesSent, n)
+}
+
+func (m *Metrics) recordReceived(n uint64) {
+	atomic.AddUint64(&m.bytesReceived, n)
+}
+
+func (m *Metrics) recordRequest(endpoint string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byStatus, ok := m.requests[endpoint]
+	if !ok {
+		byStatus = make(map[int]uint64)
+		m.requests[endpoint] = byStatus
+	}
+	byStatus[status]++
+}
+
+// Handler returns an http.Handler that serves the collected metrics in
+// Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.WriteProm(w)
+	})
+}
+
+// WriteProm writes the collected metrics to w in Prometheus text exposition
+// format.
+func (m *Metrics) WriteProm(w io.Writer) {
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, "# HELP youlab_sync_bytes_sent_total Total bytes sent to the Ralph server, including headers and retries.")
+	fmt.Fprintln(&buf, "# TYPE youlab_sync_bytes_sent_total counter")
+	fmt.Fprintf(&buf, "youlab_sync_bytes_sent_total %d\n", atomic.LoadUint64(&m.bytesSent))
+
+	fmt.Fprintln(&buf, "# HELP youlab_sync_bytes_received_total Total bytes received from the Ralph server, including headers and retries.")
+	fmt.Fprintln(&buf, "# TYPE youlab_sync_bytes_received_total counter")
+	fmt.Fprintf(&buf, "youlab_sync_bytes_received_total %d\n", atomic.LoadUint64(&m.bytesReceived))
+
+	fmt.Fprintln(&buf, "# HELP youlab_sync_requests_total Total requests by endpoint and status code.")
+	fmt.Fprintln(&buf, "# TYPE youlab_sync_requests_total counter")
+
+	m.mu.Lock()
+	for endpoint, byStatus := range m.requests {
+		for status, count := range byStatus {
+			fmt.Fprintf(&buf, "youlab_sync_requests_total{endpoint=%q,status=%q} %d\n", endpoint, fmt.Sprintf("%d", status), count)
+		}
+	}
+	m.mu.Unlock()
+
+	w.Write(buf.Bytes())
+}
+
+// countingTransport wraps an http.RoundTripper so every request/response
+// body and header is accounted for in a Metrics collector.
+type countingTransport struct {
+	next    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := req.Method + " " + req.URL.Path
+
+	var reqCounter *countingReadCloser
+	if req.Body != nil {
+		reqCounter = &countingReadCloser{ReadCloser: req.Body}
+		req.Body = reqCounter
+	}
+
+	resp, err := t.next.RoundTrip(req)
+
+	var sentBody uint64
+	if reqCounter != nil {
+		sentBody = reqCounter.read
+	}
+	t.metrics.recordSent(headerSize(req.Header) + sentBody)
+
+	if err != nil {
+		return resp, err
+	}
+
+	t.metrics.recordRequest(endpoint, resp.StatusCode)
+
+	respHeaderBytes := headerSize(resp.Header)
+	resp.Body = &countingReadCloser{
+		ReadCloser: resp.Body,
+		onClose: func(n uint64) {
+			t.metrics.recordReceived(respHeaderBytes + n)
+		},
+	}
+
+	return resp, nil
+}
+
+func headerSize(h http.Header) uint64 {
+	var buf bytes.Buffer
+	h.Write(&buf)
+	return uint64(buf.Len())
+}
+
+// countingReadCloser wraps an io.ReadCloser and tallies bytes read,
+// reporting the total via onClose (if set) when the stream is closed.
+type countingReadCloser struct {
+	io.ReadCloser
+	read    uint64
+	onClose func(uint64)
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.read += uint64(n)
+	return n, err
+}
+
+func (c *countingReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	if c.onClose != nil {
+		c.onClose(c.read)
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package ralph
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a simple token-bucket rate limiter used to cap upload and
+// download throughput for users on metered connections. A nil *tokenBucket
+// is a valid, unlimited bucket.
+type tokenBucket struct {
+	mu         sync.Mutex
+	rate       float64 // bytes per second
+	capacity   float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+// newTokenBucket returns a bucket that allows bytesPerSec bytes/second with
+// a one-second burst, or nil if bytesPerSec is not positive (unlimited).
+func newTokenBucket(bytesPerSec int64) *tokenBucket {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		rate:       float64(bytesPerSec),
+		capacity:   float64(bytesPerSec),
+		tokens:     float64(bytesPerSec),
+		lastRefill: time.Now(),
+	}
+}
+
+// wait blocks until n bytes worth of tokens are available, or ctx is
+// canceled. n may exceed the bucket's capacity (a one-second burst) -
+// since capacity is also the most tokens the bucket can ever hold, such a
+// request is metered in capacity-sized slices rather than handed to
+// waitSlice whole, which would otherwise never see enough tokens and
+// block until ctx is done.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+
+	for n > 0 {
+		slice := n
+		if max := int(b.capacity); slice > max {
+			slice = max
+		}
+		if err := b.waitSlice(ctx, slice); err != nil {
+			return err
+		}
+		n -= slice
+	}
+	return nil
+}
+
+// waitSlice blocks until n bytes worth of tokens are available, or ctx is
+// canceled. n must not exceed the bucket's capacity.
+func (b *tokenBucket) waitSlice(ctx context.Context, n int) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.lastRefill).Seconds()*b.rate)
+		b.lastRefill = now
+
+		if b.tokens >= float64(n) {
+			b.tokens -= float64(n)
+			b.mu.Unlock()
+			return nil
+		}
+
+		deficit := float64(n) - b.tokens
+		delay := time.Duration(deficit / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// throttle wraps r so every Read off it is metered through b, so a
+// download limit caps bytes as they come off the wire rather than only
+// delaying after the whole response has already been buffered in memory.
+// A nil b (unlimited) returns r unchanged.
+func (b *tokenBucket) throttle(ctx context.Context, r io.Reader) io.Reader {
+	if b == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, b: b}
+}
+
+type throttledReader struct {
+	ctx context.Context
+	r   io.Reader
+	b   *tokenBucket
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if werr := t.b.wait(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
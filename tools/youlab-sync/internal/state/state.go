@@ -0,0 +1,159 @@
+// Package state stores the local<->remote mapping that sync.Manager uses
+// to reconcile after a crash: for each synced file, the last hash seen on
+// each side and whether an operation against it was left in flight.
+//
+// It is backed by a single BoltDB file under the sync root. Every write
+// happens inside a bolt transaction, which bolt only ever commits as a
+// whole (fsync'd) unit - so a crash mid-write leaves the previous
+// consistent record in place rather than a torn one. The "pending" bucket
+// doubles as the write-ahead log: an entry is written there before the
+// corresponding local/remote mutation is attempted, and removed only once
+// it's known to have succeeded, so Store.PendingOps after a crash lists
+// exactly the operations that need to be re-diffed.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	recordsBucket = "records"
+	pendingBucket = "pending"
+)
+
+// PendingOp names a sync operation that has started against a path but
+// not yet been confirmed to have completed on both sides.
+type PendingOp string
+
+const (
+	OpUpload       PendingOp = "upload"
+	OpDownload     PendingOp = "download"
+	OpDeleteLocal  PendingOp = "delete-local"
+	OpDeleteRemote PendingOp = "delete-remote"
+)
+
+// Record is the last known local/remote state of a single synced file.
+type Record struct {
+	Path           string    `json:"path"`
+	LocalHash      string    `json:"local_hash"`
+	RemoteHash     string    `json:"remote_hash"`
+	LocalModified  time.Time `json:"local_mtime"`
+	RemoteModified time.Time `json:"remote_mtime"`
+	LastSyncedHash string    `json:"last_synced_hash"`
+	PendingOp      PendingOp `json:"pending_op,omitempty"`
+}
+
+// Store is a crash-safe, file-backed record of sync state.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open creates or opens the state database at path, creating its buckets
+// if they don't already exist.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state db: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(recordsBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(pendingBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize state db: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close releases the underlying database file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the stored record for path, or nil if there is none.
+func (s *Store) Get(path string) (*Record, error) {
+	var rec *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(recordsBucket)).Get([]byte(path))
+		if data == nil {
+			return nil
+		}
+		rec = &Record{}
+		return json.Unmarshal(data, rec)
+	})
+	return rec, err
+}
+
+// Put writes rec, keyed by rec.Path, in a single transaction.
+func (s *Store) Put(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(recordsBucket)).Put([]byte(rec.Path), data)
+	})
+}
+
+// Delete removes the record for path, if any.
+func (s *Store) Delete(path string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(recordsBucket)).Delete([]byte(path))
+	})
+}
+
+// All returns every stored record, keyed by path.
+func (s *Store) All() (map[string]Record, error) {
+	out := make(map[string]Record)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(recordsBucket)).ForEach(func(k, v []byte) error {
+			var rec Record
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			out[string(k)] = rec
+			return nil
+		})
+	})
+	return out, err
+}
+
+// MarkPending records that op is about to start for path, before the
+// corresponding local/remote mutation is attempted.
+func (s *Store) MarkPending(path string, op PendingOp) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(pendingBucket)).Put([]byte(path), []byte(op))
+	})
+}
+
+// ClearPending removes the pending marker for path once op has completed.
+func (s *Store) ClearPending(path string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(pendingBucket)).Delete([]byte(path))
+	})
+}
+
+// PendingOps returns every path with an unacknowledged operation, keyed by
+// path. A non-empty result after Open means the previous run crashed (or
+// was killed) mid-operation on these paths.
+func (s *Store) PendingOps() (map[string]PendingOp, error) {
+	out := make(map[string]PendingOp)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(pendingBucket)).ForEach(func(k, v []byte) error {
+			out[string(k)] = PendingOp(v)
+			return nil
+		})
+	})
+	return out, err
+}
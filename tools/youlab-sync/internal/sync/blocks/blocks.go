@@ -0,0 +1,112 @@
+// Package blocks implements Syncthing-style content-defined chunking: a
+// file is split into variable-size blocks at boundaries chosen by a
+// rolling hash over the content, rather than at fixed offsets. Editing a
+// few bytes in the middle of a large file only changes the blocks
+// touching the edit; every other block keeps its hash, so sync.Manager
+// can transfer just the changed blocks instead of the whole file.
+package blocks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// TargetSize is the chunker's average block size.
+	TargetSize = 128 * 1024
+	// MinSize is the smallest block the chunker will emit, other than a
+	// final trailing block shorter than MinSize.
+	MinSize = 16 * 1024
+	// MaxSize is the largest block the chunker will emit; a boundary is
+	// forced here even if the rolling hash hasn't found one, bounding
+	// worst-case memory and re-transfer cost for pathological content.
+	MaxSize = 512 * 1024
+
+	// boundaryMask is sized so that, over random content, the rolling hash
+	// satisfies roll()&boundaryMask == boundaryMask on average once every
+	// TargetSize bytes.
+	boundaryMask = TargetSize - 1
+)
+
+// Block describes one chunk of a file: its position, length, and the
+// SHA-256 hash of its content.
+type Block struct {
+	Offset int64
+	Length int
+	Hash   string
+}
+
+// Split divides content into content-defined blocks. Two files (or two
+// versions of the same file) that share long runs of identical bytes
+// produce identical blocks for those runs even if earlier bytes shifted
+// the alignment, which is what makes block-level reuse possible.
+func Split(content []byte) []Block {
+	if len(content) == 0 {
+		return nil
+	}
+
+	var result []Block
+	start := 0
+	var roll gearHash
+
+	for i := 0; i < len(content); i++ {
+		roll.roll(content[i])
+
+		length := i - start + 1
+		atEnd := i == len(content)-1
+		atBoundary := length >= MinSize && roll.value&boundaryMask == boundaryMask
+		atMax := length >= MaxSize
+
+		if atBoundary || atMax || atEnd {
+			result = append(result, newBlock(content, start, i+1))
+			start = i + 1
+			roll = gearHash{}
+		}
+	}
+
+	return result
+}
+
+// Index returns the blocks keyed by hash, so a downloader can check
+// whether a block the remote side wants is already present somewhere in
+// the local file, regardless of its offset.
+func Index(blks []Block) map[string]Block {
+	idx := make(map[string]Block, len(blks))
+	for _, b := range blks {
+		idx[b.Hash] = b
+	}
+	return idx
+}
+
+func newBlock(content []byte, start, end int) Block {
+	sum := sha256.Sum256(content[start:end])
+	return Block{
+		Offset: int64(start),
+		Length: end - start,
+		Hash:   hex.EncodeToString(sum[:]),
+	}
+}
+
+// gearHash is the rolling hash used by FastCDC/restic-style chunkers: a
+// fixed pseudo-random table turns each byte into cheap shift-and-add
+// update, which is what makes finding boundaries affordable one byte at a
+// time over large files.
+type gearHash struct {
+	value uint64
+}
+
+func (g *gearHash) roll(b byte) {
+	g.value = (g.value << 1) + gearTable[b]
+}
+
+var gearTable = func() [256]uint64 {
+	var t [256]uint64
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range t {
+		seed ^= seed << 13
+		seed ^= seed >> 7
+		seed ^= seed << 17
+		t[i] = seed
+	}
+	return t
+}()
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -13,7 +15,11 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/youlab/youlab-sync/internal/ignore"
+	"github.com/youlab/youlab-sync/internal/logging"
 	"github.com/youlab/youlab-sync/internal/ralph"
+	"github.com/youlab/youlab-sync/internal/state"
+	"github.com/youlab/youlab-sync/internal/sync/blocks"
 	"github.com/youlab/youlab-sync/internal/watcher"
 )
 
@@ -25,38 +31,115 @@ type FileState struct {
 	Modified time.Time `json:"modified"`
 	Source   string    `json:"source"` // "local" or "remote"
 	SyncedAt time.Time `json:"synced_at"`
+	// BlockHashes is the content-defined block list for this version of
+	// the file (see internal/sync/blocks), used to diff against the
+	// remote manifest and transfer only changed blocks. Empty for files
+	// synced before block support, for files transferred through the
+	// whole-file fallback, and for uploads smaller than blocks.MinSize.
+	BlockHashes []string `json:"block_hashes,omitempty"`
 }
 
 // SyncIndex stores the state of all synced files
 type SyncIndex struct {
+	// Version 2 added FileState.BlockHashes for block-level delta sync;
+	// an index written by an older build simply has empty block lists,
+	// which syncFile treats the same as "not yet block-synced".
 	Version  int                   `json:"version"`
 	UserID   string                `json:"user_id"`
 	LastSync time.Time             `json:"last_sync"`
 	Files    map[string]*FileState `json:"files"`
 }
 
+const syncIndexVersion = 2
+
+// ConflictEvent describes a file found to have diverged on both the local
+// and remote sides since the last successful sync.
+type ConflictEvent struct {
+	Path         string    `json:"path"`
+	ConflictPath string    `json:"conflict_path,omitempty"` // set whenever the losing version was saved as a conflict file
+	Strategy     string    `json:"strategy"`
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
 // Manager handles bidirectional file synchronization
 type Manager struct {
-	client         *ralph.Client
-	localPath      string
-	ignorePatterns []string
-	indexPath      string
+	client               *ralph.Client
+	localPath            string
+	matcher              *ignore.Matcher
+	indexPath            string
+	chunkSize            int64
+	chunkUploadThreshold int64
+	uploadsPath          string
+	conflictsPath        string
+	conflictStrategy     string
+	userID               string
+	stagingDir           string
+	numHashers           int
+
+	// state is the crash-safe journal of in-flight operations. It
+	// complements index (below): index is the last-synced-hash bookkeeping
+	// used for ordinary 3-way conflict detection, while state additionally
+	// tracks operations that have started but not yet been confirmed to
+	// have completed on both sides, so a crash mid-upload or mid-download
+	// is recoverable instead of leaving a silently divergent tree.
+	state *state.Store
 
 	mu    sync.RWMutex
 	index *SyncIndex
+
+	uploadsMu sync.Mutex
+	uploads   map[string]string // relPath -> in-progress upload session ID
+
+	conflictsMu sync.Mutex
+	conflicts   map[string]ConflictEvent // relPath -> most recent outstanding conflict
+	conflictCh  chan ConflictEvent
 }
 
-// NewManager creates a new sync manager
-func NewManager(client *ralph.Client, localPath string, ignorePatterns []string) (*Manager, error) {
+// NewManager creates a new sync manager. matcher is shared with
+// watcher.Watcher so ignore behavior is identical across the initial walk,
+// live events, and remote diffing. chunkSize is the size of each chunk sent
+// once a file goes through the chunked-upload path; chunkUploadThreshold is
+// the file size above which uploads take that path via
+// ralph.Client.PutFileChunked instead of a single PutFile request - pass 0
+// for chunkSize to use ralph's default chunk size, and 0 for
+// chunkUploadThreshold to turn the chunked path off. conflictStrategy selects how a
+// file changed on both sides is resolved: "newer", "local", "remote", or
+// "manual". stagingDir is where downloaded content is staged before being
+// renamed into place; pass "" to use the default of
+// "<localPath>/.youlab-sync/tmp". numHashers is the size of the worker pool
+// scanLocalFiles uses to hash files concurrently; pass 0 or less to hash
+// with a single worker.
+func NewManager(client *ralph.Client, localPath string, matcher *ignore.Matcher, chunkSize, chunkUploadThreshold int64, conflictStrategy, stagingDir string, numHashers int) (*Manager, error) {
+	if conflictStrategy == "" {
+		conflictStrategy = "newer"
+	}
+	if stagingDir == "" {
+		stagingDir = filepath.Join(localPath, ".youlab-sync", "tmp")
+	}
+	if numHashers < 1 {
+		numHashers = 1
+	}
+
 	m := &Manager{
-		client:         client,
-		localPath:      localPath,
-		ignorePatterns: ignorePatterns,
-		indexPath:      filepath.Join(localPath, ".youlab-sync", "index.json"),
+		client:               client,
+		localPath:            localPath,
+		matcher:              matcher,
+		indexPath:            filepath.Join(localPath, ".youlab-sync", "index.json"),
+		uploadsPath:          filepath.Join(localPath, ".youlab-sync", "uploads.json"),
+		conflictsPath:        filepath.Join(localPath, ".youlab-sync", "conflicts.json"),
+		chunkSize:            chunkSize,
+		chunkUploadThreshold: chunkUploadThreshold,
+		conflictStrategy:     conflictStrategy,
+		stagingDir:           stagingDir,
+		numHashers:           numHashers,
+		userID:               client.UserID(),
 		index: &SyncIndex{
-			Version: 1,
+			Version: syncIndexVersion,
 			Files:   make(map[string]*FileState),
 		},
+		uploads:    make(map[string]string),
+		conflicts:  make(map[string]ConflictEvent),
+		conflictCh: make(chan ConflictEvent, 100),
 	}
 
 	// Ensure sync directory exists
@@ -65,14 +148,164 @@ func NewManager(client *ralph.Client, localPath string, ignorePatterns []string)
 		return nil, fmt.Errorf("failed to create sync directory: %w", err)
 	}
 
+	stateStore, err := state.Open(filepath.Join(syncDir, "state.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sync state: %w", err)
+	}
+	m.state = stateStore
+
+	if err := os.MkdirAll(m.stagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	if err := m.reapOrphanedStagingFiles(); err != nil {
+		logrus.Warnf("Failed to reap orphaned staging files: %v", err)
+	}
+
 	// Load existing index
 	if err := m.loadIndex(); err != nil {
 		logrus.Warnf("Failed to load sync index: %v", err)
 	}
 
+	// Load any upload sessions left in-progress by a previous run
+	if err := m.loadUploadSessions(); err != nil {
+		logrus.Warnf("Failed to load upload sessions: %v", err)
+	}
+
+	// Load any conflicts left outstanding by a previous run
+	if err := m.loadConflicts(); err != nil {
+		logrus.Warnf("Failed to load conflicts: %v", err)
+	}
+
 	return m, nil
 }
 
+// Conflicts returns the channel on which newly detected conflicts are
+// emitted as they're resolved by the configured conflict strategy.
+func (m *Manager) Conflicts() <-chan ConflictEvent {
+	return m.conflictCh
+}
+
+// Close releases the sync state database. Callers should defer this after
+// a successful NewManager call.
+func (m *Manager) Close() error {
+	return m.state.Close()
+}
+
+// Reconcile inspects the crash journal left by a previous run and brings
+// any interrupted operations back into a known state before the first
+// full sync. This is what makes a crash mid-upload or mid-download
+// recoverable instead of leaving a silently divergent tree: an upload
+// whose pending marker is still set but whose bytes actually reached the
+// server (the ack was just lost) is recognized as complete rather than
+// retried from scratch, and anything left genuinely unresolved is simply
+// unmarked so the following FullSync re-diffs it from scratch.
+func (m *Manager) Reconcile(ctx context.Context) error {
+	pending, err := m.state.PendingOps()
+	if err != nil {
+		return fmt.Errorf("failed to read pending operations: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	logrus.Infof("Reconciling %d pending operation(s) from a previous run", len(pending))
+
+	remoteIndex, err := m.client.ListFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list remote files for reconciliation: %w", err)
+	}
+
+	for path, op := range pending {
+		rec, err := m.state.Get(path)
+		if err != nil || rec == nil {
+			m.clearPending(path, "")
+			continue
+		}
+
+		local, err := m.localFileState(path)
+		if err != nil {
+			logrus.Warnf("Reconcile: failed to stat %s: %v", path, err)
+			continue
+		}
+
+		switch op {
+		case state.OpUpload:
+			if remote, ok := remoteIndex.Files[path]; ok && local != nil && remote.Hash == local.Hash {
+				logging.Debugf(logging.Sync, "Reconcile: upload of %s completed before the crash, clearing pending marker", path)
+				m.clearPending(path, remote.Hash)
+				continue
+			}
+			logrus.Infof("Reconcile: upload of %s did not complete, will be re-diffed", path)
+
+		case state.OpDownload:
+			if remote, ok := remoteIndex.Files[path]; ok && local != nil && local.Hash == remote.Hash {
+				logging.Debugf(logging.Sync, "Reconcile: download of %s completed before the crash, clearing pending marker", path)
+				m.clearPending(path, local.Hash)
+				continue
+			}
+			logrus.Infof("Reconcile: download of %s did not complete, will be re-diffed", path)
+
+		default:
+			logrus.Infof("Reconcile: %s was mid-%s, will be re-diffed", path, op)
+		}
+
+		m.clearPending(path, "")
+	}
+
+	return nil
+}
+
+// markPending records that op is about to start for path in the crash
+// journal, before the corresponding local/remote mutation is attempted.
+func (m *Manager) markPending(path string, op state.PendingOp) {
+	if err := m.state.MarkPending(path, op); err != nil {
+		logrus.Warnf("Failed to record pending %s for %s: %v", op, path, err)
+	}
+}
+
+// clearPending removes the pending marker for path. If syncedHash is
+// non-empty, the record's last-synced/local/remote hashes are also
+// advanced to it, reflecting that both sides are now known to agree.
+func (m *Manager) clearPending(path, syncedHash string) {
+	rec, _ := m.state.Get(path)
+	if rec == nil {
+		rec = &state.Record{Path: path}
+	}
+	if err := m.state.ClearPending(path); err != nil {
+		logrus.Warnf("Failed to clear pending marker for %s: %v", path, err)
+	}
+	rec.PendingOp = ""
+	if syncedHash != "" {
+		rec.LastSyncedHash = syncedHash
+		rec.LocalHash = syncedHash
+		rec.RemoteHash = syncedHash
+	}
+	if err := m.state.Put(*rec); err != nil {
+		logrus.Warnf("Failed to clear pending op for %s: %v", path, err)
+	}
+}
+
+// ResumeUploads continues any chunked uploads that were in progress when
+// the daemon last stopped, querying the server for which chunks it has
+// already received so only the remainder is sent.
+func (m *Manager) ResumeUploads(ctx context.Context) error {
+	m.uploadsMu.Lock()
+	pending := make(map[string]string, len(m.uploads))
+	for path, sessionID := range m.uploads {
+		pending[path] = sessionID
+	}
+	m.uploadsMu.Unlock()
+
+	for relPath, sessionID := range pending {
+		logrus.Infof("Resuming upload: %s (session %s)", relPath, sessionID)
+		if err := m.uploadFileWithSession(ctx, relPath, sessionID); err != nil {
+			logrus.Errorf("Failed to resume upload %s: %v", relPath, err)
+		}
+	}
+
+	return nil
+}
+
 // FullSync performs a complete bidirectional sync
 func (m *Manager) FullSync(ctx context.Context) error {
 	logrus.Info("Starting full sync...")
@@ -133,7 +366,7 @@ func (m *Manager) FullSync(ctx context.Context) error {
 
 // HandleLocalChange processes a local file change event
 func (m *Manager) HandleLocalChange(ctx context.Context, event watcher.Event) error {
-	logrus.Debugf("Handling local change: %s (%s)", event.Path, event.Operation)
+	logging.Debugf(logging.Sync, "Handling local change: %s (%s)", event.Path, event.Operation)
 
 	switch event.Operation {
 	case watcher.OpCreate, watcher.OpWrite:
@@ -148,8 +381,61 @@ func (m *Manager) HandleLocalChange(ctx context.Context, event watcher.Event) er
 	return nil
 }
 
+// HandleRemoteChange processes a change event pushed by the server. It goes
+// through the same conflict-aware path as FullSync, so a local edit racing
+// a remote push is still caught instead of being blindly overwritten.
+func (m *Manager) HandleRemoteChange(ctx context.Context, event ralph.RemoteEvent) error {
+	logging.Debugf(logging.Sync, "Handling remote change: %s (%s)", event.Path, event.Op)
+
+	if event.Op == "remove" {
+		if m.getIndexedState(event.Path) == nil {
+			return nil
+		}
+		return m.deleteLocalFile(event.Path)
+	}
+
+	remoteMeta := &ralph.FileMetadata{
+		Path:     event.Path,
+		Hash:     event.Hash,
+		Modified: event.Modified,
+	}
+
+	local, err := m.localFileState(event.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stat local file %s: %w", event.Path, err)
+	}
+
+	return m.syncFile(ctx, event.Path, remoteMeta, local, m.getIndexedState(event.Path))
+}
+
+// localFileState reads and hashes the given path relative to localPath,
+// returning nil (with no error) if the file does not exist locally.
+func (m *Manager) localFileState(relPath string) (*FileState, error) {
+	fullPath := filepath.Join(m.localPath, relPath)
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileState{
+		Path:     relPath,
+		Hash:     calculateHash(content),
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+	}, nil
+}
+
 func (m *Manager) syncFile(ctx context.Context, path string, remote *ralph.FileMetadata, local *FileState, indexed *FileState) error {
-	logrus.Debugf("Syncing %s: remote=%v local=%v indexed=%v", path, remote != nil, local != nil, indexed != nil)
+	logging.Debugf(logging.Sync, "Syncing %s: remote=%v local=%v indexed=%v", path, remote != nil, local != nil, indexed != nil)
 
 	switch {
 	case remote != nil && local != nil:
@@ -160,7 +446,13 @@ func (m *Manager) syncFile(ctx context.Context, path string, remote *ralph.FileM
 			return nil
 		}
 
-		// Different content - resolve conflict
+		// A true conflict is a file that changed on both sides since the
+		// last hash we successfully synced - not just "differs from
+		// remote", which is also true for an ordinary one-sided edit.
+		if indexed != nil && local.Hash != indexed.Hash && remote.Hash != indexed.Hash {
+			return m.resolveConflict(ctx, path, remote, local)
+		}
+
 		// Use last-modified time, prefer local on tie
 		if local.Modified.After(remote.Modified) {
 			logrus.Infof("Local file newer, uploading: %s", path)
@@ -205,7 +497,120 @@ func (m *Manager) syncFile(ctx context.Context, path string, remote *ralph.FileM
 	return nil
 }
 
+// resolveConflict applies m.conflictStrategy to a file that has changed on
+// both the local and remote sides since the last successful sync. Every
+// strategy preserves the losing side as a Syncthing-style conflict-file
+// sibling rather than silently discarding it - that's the whole point of
+// detecting a conflict instead of just picking a winner by timestamp.
+func (m *Manager) resolveConflict(ctx context.Context, path string, remote *ralph.FileMetadata, local *FileState) error {
+	logrus.Warnf("Conflict detected on %s (strategy: %s)", path, m.conflictStrategy)
+
+	switch m.conflictStrategy {
+	case "local":
+		return m.resolveConflictLocalWins(ctx, path, "local")
+
+	case "remote":
+		return m.resolveConflictRemoteWins(ctx, path, "remote")
+
+	case "manual":
+		return m.resolveConflictRemoteWins(ctx, path, "manual")
+
+	case "newer":
+		fallthrough
+	default:
+		if local.Modified.After(remote.Modified) {
+			return m.resolveConflictLocalWins(ctx, path, "newer")
+		}
+		return m.resolveConflictRemoteWins(ctx, path, "newer")
+	}
+}
+
+// resolveConflictLocalWins uploads the local version of path as the
+// winner, but first downloads and preserves remote's current (losing)
+// content as a conflict-file sibling, so "local" and "newer-wins-local"
+// resolutions never destroy the remote side without a backup.
+func (m *Manager) resolveConflictLocalWins(ctx context.Context, path, strategy string) error {
+	remoteContent, _, err := m.client.GetFile(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote version for conflict backup: %w", err)
+	}
+
+	var conflictPath string
+	if remoteContent != nil {
+		conflictPath, err = m.writeConflictFile(path, remoteContent)
+		if err != nil {
+			return err
+		}
+
+		metadata, err := m.client.PutFile(ctx, conflictPath, remoteContent)
+		if err != nil {
+			return fmt.Errorf("failed to upload conflict copy: %w", err)
+		}
+		m.updateIndex(conflictPath, metadata.Hash, metadata.Size, time.Now(), "remote")
+		logrus.Infof("Conflict on %s resolved (%s): remote version saved as %s", path, strategy, conflictPath)
+	}
+
+	m.recordConflict(path, strategy, conflictPath)
+	return m.uploadFile(ctx, path)
+}
+
+// resolveConflictRemoteWins downloads the remote version of path as the
+// winner, but first preserves the local (losing) content as a
+// path.sync-conflict-YYYYMMDD-HHMMSS-<shorthash>.ext sibling and uploads
+// it, so "remote", "manual", and "newer-wins-remote" resolutions never
+// destroy the local side without a backup.
+func (m *Manager) resolveConflictRemoteWins(ctx context.Context, path, strategy string) error {
+	fullPath := filepath.Join(m.localPath, path)
+
+	localContent, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to read local file: %w", err)
+	}
+
+	conflictPath, err := m.writeConflictFile(path, localContent)
+	if err != nil {
+		return err
+	}
+
+	if err := m.downloadFile(ctx, path); err != nil {
+		return fmt.Errorf("failed to download remote version: %w", err)
+	}
+
+	metadata, err := m.client.PutFile(ctx, conflictPath, localContent)
+	if err != nil {
+		return fmt.Errorf("failed to upload conflict copy: %w", err)
+	}
+	m.updateIndex(conflictPath, metadata.Hash, metadata.Size, time.Now(), "local")
+
+	m.recordConflict(path, strategy, conflictPath)
+	logrus.Infof("Conflict on %s resolved (%s): local version saved as %s", path, strategy, conflictPath)
+	return nil
+}
+
+// writeConflictFile saves content as a Syncthing-style
+// path.sync-conflict-YYYYMMDD-HHMMSS-<shorthash>.ext sibling of path, so
+// the loser of a conflict resolution is recoverable instead of discarded.
+func (m *Manager) writeConflictFile(path string, content []byte) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	shortHash := calculateHash(content)[:7]
+	conflictPath := fmt.Sprintf("%s.sync-conflict-%s-%s%s", base, time.Now().Format("20060102-150405"), shortHash, ext)
+
+	conflictFullPath := filepath.Join(m.localPath, conflictPath)
+	if err := os.WriteFile(conflictFullPath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write conflict copy: %w", err)
+	}
+
+	return conflictPath, nil
+}
+
 func (m *Manager) uploadFile(ctx context.Context, relPath string) error {
+	return m.uploadFileWithSession(ctx, relPath, m.getUploadSession(relPath))
+}
+
+// uploadFileWithSession uploads relPath, resuming sessionID if it is
+// non-empty and large enough to have used the chunked path.
+func (m *Manager) uploadFileWithSession(ctx context.Context, relPath, sessionID string) error {
 	fullPath := filepath.Join(m.localPath, relPath)
 
 	content, err := os.ReadFile(fullPath)
@@ -221,13 +626,39 @@ func (m *Manager) uploadFile(ctx context.Context, relPath string) error {
 
 	// Skip binary files
 	if isBinaryFile(content) {
-		logrus.Debugf("Skipping binary file: %s", relPath)
+		logging.Debugf(logging.Sync, "Skipping binary file: %s", relPath)
 		return nil
 	}
 
-	metadata, err := m.client.PutFile(ctx, relPath, content)
-	if err != nil {
-		return fmt.Errorf("failed to upload file: %w", err)
+	m.markPending(relPath, state.OpUpload)
+
+	// Try block-level delta sync first, so an edit to a large file only
+	// sends the blocks that actually changed; fall back to whole-file (or
+	// chunked whole-file) transfer if the server doesn't support it.
+	var metadata *ralph.FileMetadata
+	var blockHashes []string
+	if len(content) >= blocks.MinSize {
+		metadata, blockHashes, err = m.uploadFileBlocks(ctx, relPath, content)
+		if err != nil && !errors.Is(err, ralph.ErrBlocksUnsupported) {
+			return fmt.Errorf("failed to upload file blocks: %w", err)
+		}
+	}
+
+	if metadata == nil {
+		if m.chunkUploadThreshold > 0 && int64(len(content)) > m.chunkUploadThreshold {
+			var sid string
+			sid, metadata, err = m.client.PutFileChunked(ctx, relPath, content, m.chunkSize, sessionID)
+			if err != nil {
+				m.setUploadSession(relPath, sid)
+				return fmt.Errorf("failed to upload file: %w", err)
+			}
+			m.clearUploadSession(relPath)
+		} else {
+			metadata, err = m.client.PutFile(ctx, relPath, content)
+			if err != nil {
+				return fmt.Errorf("failed to upload file: %w", err)
+			}
+		}
 	}
 
 	info, err := os.Stat(fullPath)
@@ -236,14 +667,64 @@ func (m *Manager) uploadFile(ctx context.Context, relPath string) error {
 	}
 
 	m.updateIndex(relPath, metadata.Hash, metadata.Size, info.ModTime(), "local")
+	m.setBlockHashes(relPath, blockHashes)
+	m.clearPending(relPath, metadata.Hash)
 	logrus.Infof("Uploaded: %s", relPath)
 	return nil
 }
 
+// uploadFileBlocks uploads content for relPath via the block-manifest API:
+// content is split into content-defined blocks, the server is asked which
+// of their hashes it's missing, and only those are sent before submitting
+// the manifest it assembles the file from. Returns
+// ralph.ErrBlocksUnsupported if the server doesn't implement block
+// storage.
+func (m *Manager) uploadFileBlocks(ctx context.Context, relPath string, content []byte) (*ralph.FileMetadata, []string, error) {
+	split := blocks.Split(content)
+
+	hashes := make([]string, len(split))
+	for i, b := range split {
+		hashes[i] = b.Hash
+	}
+
+	have, err := m.client.HasBlocks(ctx, hashes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refs := make([]ralph.BlockRef, len(split))
+	for i, b := range split {
+		if !have[b.Hash] {
+			if err := m.client.PutBlock(ctx, b.Hash, content[b.Offset:b.Offset+int64(b.Length)]); err != nil {
+				return nil, nil, err
+			}
+		}
+		refs[i] = ralph.BlockRef{Hash: b.Hash, Offset: b.Offset, Length: b.Length}
+	}
+
+	metadata, err := m.client.PutFileManifest(ctx, relPath, refs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return metadata, hashes, nil
+}
+
 func (m *Manager) downloadFile(ctx context.Context, relPath string) error {
-	content, metadata, err := m.client.GetFile(ctx, relPath)
+	content, metadata, blockHashes, err := m.downloadFileBlocks(ctx, relPath)
 	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
+		if !errors.Is(err, ralph.ErrBlocksUnsupported) {
+			return fmt.Errorf("failed to download file blocks: %w", err)
+		}
+
+		// Server doesn't implement block storage (or, rarely, the file was
+		// deleted out from under a manifest fetch) - whole-file GetFile
+		// handles both: it either succeeds or reports a nil content deletion.
+		content, metadata, err = m.client.GetFile(ctx, relPath)
+		if err != nil {
+			return fmt.Errorf("failed to download file: %w", err)
+		}
+		blockHashes = nil
 	}
 
 	if content == nil {
@@ -251,31 +732,146 @@ func (m *Manager) downloadFile(ctx context.Context, relPath string) error {
 		return m.deleteLocalFile(relPath)
 	}
 
+	m.markPending(relPath, state.OpDownload)
+
 	fullPath := filepath.Join(m.localPath, relPath)
 
-	// Create directory if needed
-	dir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	if err := m.writeStaged(fullPath, content, metadata.Modified); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
 
-	if err := os.WriteFile(fullPath, content, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	m.updateIndex(relPath, metadata.Hash, metadata.Size, metadata.Modified, "remote")
+	m.setBlockHashes(relPath, blockHashes)
+	m.clearPending(relPath, metadata.Hash)
+	logrus.Infof("Downloaded: %s", relPath)
+	return nil
+}
+
+// downloadFileBlocks downloads relPath via the block-manifest API: it
+// reuses any block already present in the local file (regardless of its
+// offset there) by hash, and fetches only the ones that changed. Returns
+// ralph.ErrBlocksUnsupported if the server doesn't implement block
+// storage.
+func (m *Manager) downloadFileBlocks(ctx context.Context, relPath string) ([]byte, *ralph.FileMetadata, []string, error) {
+	remoteBlocks, metadata, err := m.client.GetFileManifest(ctx, relPath)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	// Set modification time to match remote
-	if !metadata.Modified.IsZero() {
-		if err := os.Chtimes(fullPath, metadata.Modified, metadata.Modified); err != nil {
-			logrus.Warnf("Failed to set file modification time: %v", err)
+	fullPath := filepath.Join(m.localPath, relPath)
+	localContent, _ := os.ReadFile(fullPath) // best-effort; fine if absent
+	localIndex := blocks.Index(blocks.Split(localContent))
+
+	content := make([]byte, metadata.Size)
+	hashes := make([]string, len(remoteBlocks))
+
+	for i, ref := range remoteBlocks {
+		hashes[i] = ref.Hash
+
+		data, reused := reuseLocalBlock(localContent, localIndex, ref)
+		if !reused {
+			data, err = m.client.GetBlock(ctx, ref.Hash)
+			if err != nil {
+				return nil, nil, nil, err
+			}
 		}
+		copy(content[ref.Offset:ref.Offset+int64(ref.Length)], data)
+	}
+
+	return content, metadata, hashes, nil
+}
+
+// writeStaged writes content to a temp file inside m.stagingDir, fsyncs it,
+// sets its mtime to modified, and renames it into place at destPath. Staging
+// first means a crash or full disk during the write never leaves a partial
+// file at destPath for the watcher (or the user) to see; the final
+// os.Rename is atomic as long as stagingDir and destPath share a
+// filesystem, which NewManager's default ensures.
+func (m *Manager) writeStaged(destPath string, content []byte, modified time.Time) error {
+	stagingPath := filepath.Join(m.stagingDir, calculateHash(content)+".partial")
+
+	f, err := os.OpenFile(stagingPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create staging file: %w", err)
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(stagingPath)
+		return fmt.Errorf("failed to write staging file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(stagingPath)
+		return fmt.Errorf("failed to sync staging file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(stagingPath)
+		return fmt.Errorf("failed to close staging file: %w", err)
+	}
+
+	if !modified.IsZero() {
+		if err := os.Chtimes(stagingPath, modified, modified); err != nil {
+			logrus.Warnf("Failed to set mtime on staged file: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		os.Remove(stagingPath)
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	if err := os.Rename(stagingPath, destPath); err != nil {
+		os.Remove(stagingPath)
+		return fmt.Errorf("failed to rename staged file into place: %w", err)
 	}
 
-	m.updateIndex(relPath, metadata.Hash, metadata.Size, metadata.Modified, "remote")
-	logrus.Infof("Downloaded: %s", relPath)
 	return nil
 }
 
+// reapOrphanedStagingFiles removes any ".partial" file left in m.stagingDir
+// by a run that crashed between writing a staged download and renaming it
+// into place. It's called once from NewManager, before the first sync, so
+// a crash doesn't leak staging files across restarts.
+func (m *Manager) reapOrphanedStagingFiles() error {
+	entries, err := os.ReadDir(m.stagingDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read staging directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".partial") {
+			continue
+		}
+
+		path := filepath.Join(m.stagingDir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			logrus.Warnf("Failed to reap orphaned staging file %s: %v", path, err)
+			continue
+		}
+		logging.Debugf(logging.Sync, "Reaped orphaned staging file: %s", path)
+	}
+
+	return nil
+}
+
+// reuseLocalBlock returns the bytes of a remote block from the local file
+// if a block with the same hash and length is already present there,
+// regardless of offset.
+func reuseLocalBlock(localContent []byte, localIndex map[string]blocks.Block, ref ralph.BlockRef) ([]byte, bool) {
+	lb, ok := localIndex[ref.Hash]
+	if !ok || lb.Length != ref.Length {
+		return nil, false
+	}
+	return localContent[lb.Offset : lb.Offset+int64(lb.Length)], true
+}
+
 func (m *Manager) deleteLocalFile(relPath string) error {
+	m.markPending(relPath, state.OpDeleteLocal)
+
 	fullPath := filepath.Join(m.localPath, relPath)
 
 	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
@@ -283,24 +879,74 @@ func (m *Manager) deleteLocalFile(relPath string) error {
 	}
 
 	m.removeFromIndex(relPath)
+	if err := m.state.Delete(relPath); err != nil {
+		logrus.Warnf("Failed to clear sync state for %s: %v", relPath, err)
+	}
+	if err := m.state.ClearPending(relPath); err != nil {
+		logrus.Warnf("Failed to clear pending marker for %s: %v", relPath, err)
+	}
 	logrus.Infof("Deleted locally: %s", relPath)
 	return nil
 }
 
 func (m *Manager) deleteRemoteFile(ctx context.Context, relPath string) error {
+	m.markPending(relPath, state.OpDeleteRemote)
+
 	if err := m.client.DeleteFile(ctx, relPath); err != nil {
 		return fmt.Errorf("failed to delete remote file: %w", err)
 	}
 
 	m.removeFromIndex(relPath)
+	if err := m.state.Delete(relPath); err != nil {
+		logrus.Warnf("Failed to clear sync state for %s: %v", relPath, err)
+	}
+	if err := m.state.ClearPending(relPath); err != nil {
+		logrus.Warnf("Failed to clear pending marker for %s: %v", relPath, err)
+	}
 	logrus.Infof("Deleted remotely: %s", relPath)
 	return nil
 }
 
+// scanLocalFiles walks the local tree and hashes every non-ignored,
+// non-binary file. The walk itself stays on the calling goroutine (so
+// directory-order .youlabignore loading stays deterministic), but hashing
+// fans out across m.numHashers workers, each streaming its file through
+// sha256 via io.Copy rather than loading it fully into memory - the same
+// approach Syncthing's hasher pool uses to keep large-workspace scans off
+// a single core and off the heap.
 func (m *Manager) scanLocalFiles() (map[string]*FileState, error) {
+	type job struct {
+		path    string
+		relPath string
+	}
+
+	jobs := make(chan job, m.numHashers*2)
 	files := make(map[string]*FileState)
+	var filesMu sync.Mutex
+
+	var wg sync.WaitGroup
+	for i := 0; i < m.numHashers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				state, err := m.hashLocalFile(j.path, j.relPath)
+				if err != nil {
+					logrus.Warnf("Failed to hash file %s: %v", j.relPath, err)
+					continue
+				}
+				if state == nil {
+					continue // binary file, skipped
+				}
+
+				filesMu.Lock()
+				files[j.relPath] = state
+				filesMu.Unlock()
+			}
+		}()
+	}
 
-	err := filepath.WalkDir(m.localPath, func(path string, d fs.DirEntry, err error) error {
+	walkErr := filepath.WalkDir(m.localPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
@@ -310,6 +956,9 @@ func (m *Manager) scanLocalFiles() (map[string]*FileState, error) {
 		if err != nil {
 			return nil
 		}
+		if relPath == "." {
+			relPath = ""
+		}
 
 		// Skip the sync directory itself
 		if strings.HasPrefix(relPath, ".youlab-sync") {
@@ -319,68 +968,73 @@ func (m *Manager) scanLocalFiles() (map[string]*FileState, error) {
 			return nil
 		}
 
-		// Skip ignored files/directories
-		if m.shouldIgnore(relPath) {
-			if d.IsDir() {
+		if d.IsDir() {
+			// Pick up any .youlabignore scoped to this directory before
+			// deciding whether to descend into it
+			if err := m.matcher.LoadDir(path, relPath); err != nil {
+				logrus.Warnf("Failed to load .youlabignore in %s: %v", path, err)
+			}
+			if relPath != "" && m.matcher.Match(relPath, true) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// Skip directories
-		if d.IsDir() {
-			return nil
-		}
-
-		// Read file
-		content, err := os.ReadFile(path)
-		if err != nil {
-			logrus.Warnf("Failed to read file %s: %v", path, err)
-			return nil
-		}
-
-		// Skip binary files
-		if isBinaryFile(content) {
-			return nil
-		}
-
-		info, err := d.Info()
-		if err != nil {
+		// Skip ignored files
+		if m.matcher.Match(relPath, false) {
 			return nil
 		}
 
-		hash := calculateHash(content)
-
-		files[relPath] = &FileState{
-			Path:     relPath,
-			Hash:     hash,
-			Size:     info.Size(),
-			Modified: info.ModTime(),
-			Source:   "local",
-		}
-
+		jobs <- job{path: path, relPath: relPath}
 		return nil
 	})
 
-	return files, err
+	close(jobs)
+	wg.Wait()
+
+	return files, walkErr
 }
 
-func (m *Manager) shouldIgnore(path string) bool {
-	parts := strings.Split(path, string(filepath.Separator))
+// hashLocalFile streams path through sha256 without reading it fully into
+// memory, returning (nil, nil) if the file looks binary. It peeks at the
+// first isBinaryFile-sized chunk to make that call, then feeds the same
+// bytes into the hash before copying the rest of the file through it.
+func (m *Manager) hashLocalFile(path, relPath string) (*FileState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-	for _, pattern := range m.ignorePatterns {
-		for _, part := range parts {
-			if matched, _ := filepath.Match(pattern, part); matched {
-				return true
-			}
-		}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
 
-		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
-			return true
-		}
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	if isBinaryFile(head) {
+		return nil, nil
 	}
 
-	return false
+	h := sha256.New()
+	h.Write(head)
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	return &FileState{
+		Path:     relPath,
+		Hash:     fmt.Sprintf("%x", h.Sum(nil)),
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		Source:   "local",
+	}, nil
 }
 
 func (m *Manager) getIndexedState(path string) *FileState {
@@ -403,12 +1057,183 @@ func (m *Manager) updateIndex(path, hash string, size int64, modified time.Time,
 	}
 }
 
+// setBlockHashes records the content-defined block list produced for the
+// version of path just transferred, if block-level sync was used for it.
+// A nil hashes is a no-op; updateIndex has already replaced the entry, so a
+// version sent through the whole-file fallback is left with no block list.
+func (m *Manager) setBlockHashes(path string, hashes []string) {
+	if hashes == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.index.Files[path]; ok {
+		existing.BlockHashes = hashes
+	}
+}
+
 func (m *Manager) removeFromIndex(path string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.index.Files, path)
 }
 
+// recordConflict emits a ConflictEvent on Conflicts() and persists it to
+// conflicts.json so the `youlab-sync conflicts` subcommand can list and
+// re-resolve it later, even from a separate process.
+func (m *Manager) recordConflict(path, strategy, conflictPath string) {
+	event := ConflictEvent{
+		Path:         path,
+		ConflictPath: conflictPath,
+		Strategy:     strategy,
+		DetectedAt:   time.Now(),
+	}
+
+	select {
+	case m.conflictCh <- event:
+	default:
+		logrus.Warnf("Conflict channel full, dropping conflict event for: %s", path)
+	}
+
+	m.conflictsMu.Lock()
+	m.conflicts[path] = event
+	m.conflictsMu.Unlock()
+
+	if err := m.saveConflicts(); err != nil {
+		logrus.Warnf("Failed to persist conflict record for %s: %v", path, err)
+	}
+}
+
+// ListConflicts returns the conflicts recorded by past sync runs that have
+// not yet been cleared by ResolveConflict.
+func (m *Manager) ListConflicts() []ConflictEvent {
+	m.conflictsMu.Lock()
+	defer m.conflictsMu.Unlock()
+
+	out := make([]ConflictEvent, 0, len(m.conflicts))
+	for _, e := range m.conflicts {
+		out = append(out, e)
+	}
+	return out
+}
+
+// ResolveConflict manually resolves an outstanding conflict at path by
+// forcing "local" or "remote" to win, then clears its record.
+func (m *Manager) ResolveConflict(ctx context.Context, path, resolution string) error {
+	m.conflictsMu.Lock()
+	_, ok := m.conflicts[path]
+	m.conflictsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no outstanding conflict for %s", path)
+	}
+
+	var err error
+	switch resolution {
+	case "local":
+		err = m.uploadFile(ctx, path)
+	case "remote":
+		err = m.downloadFile(ctx, path)
+	default:
+		return fmt.Errorf("unknown resolution %q (want \"local\" or \"remote\")", resolution)
+	}
+	if err != nil {
+		return err
+	}
+
+	m.conflictsMu.Lock()
+	delete(m.conflicts, path)
+	m.conflictsMu.Unlock()
+
+	return m.saveConflicts()
+}
+
+func (m *Manager) loadConflicts() error {
+	data, err := os.ReadFile(m.conflictsPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m.conflictsMu.Lock()
+	defer m.conflictsMu.Unlock()
+	return json.Unmarshal(data, &m.conflicts)
+}
+
+func (m *Manager) saveConflicts() error {
+	m.conflictsMu.Lock()
+	data, err := json.MarshalIndent(m.conflicts, "", "  ")
+	m.conflictsMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(m.conflictsPath, data, 0644)
+}
+
+func (m *Manager) getUploadSession(relPath string) string {
+	m.uploadsMu.Lock()
+	defer m.uploadsMu.Unlock()
+	return m.uploads[relPath]
+}
+
+func (m *Manager) setUploadSession(relPath, sessionID string) {
+	if sessionID == "" {
+		return
+	}
+
+	m.uploadsMu.Lock()
+	m.uploads[relPath] = sessionID
+	m.uploadsMu.Unlock()
+
+	if err := m.saveUploadSessions(); err != nil {
+		logrus.Warnf("Failed to persist upload session for %s: %v", relPath, err)
+	}
+}
+
+func (m *Manager) clearUploadSession(relPath string) {
+	m.uploadsMu.Lock()
+	_, existed := m.uploads[relPath]
+	delete(m.uploads, relPath)
+	m.uploadsMu.Unlock()
+
+	if existed {
+		if err := m.saveUploadSessions(); err != nil {
+			logrus.Warnf("Failed to persist upload session removal for %s: %v", relPath, err)
+		}
+	}
+}
+
+func (m *Manager) loadUploadSessions() error {
+	data, err := os.ReadFile(m.uploadsPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m.uploadsMu.Lock()
+	defer m.uploadsMu.Unlock()
+	return json.Unmarshal(data, &m.uploads)
+}
+
+func (m *Manager) saveUploadSessions() error {
+	m.uploadsMu.Lock()
+	data, err := json.MarshalIndent(m.uploads, "", "  ")
+	m.uploadsMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(m.uploadsPath, data, 0644)
+}
+
 func (m *Manager) loadIndex() error {
 	data, err := os.ReadFile(m.indexPath)
 	if os.IsNotExist(err) {
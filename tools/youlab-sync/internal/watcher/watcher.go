@@ -10,8 +10,20 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
+	"github.com/youlab/youlab-sync/internal/ignore"
+	"github.com/youlab/youlab-sync/internal/logging"
 )
 
+// internalDirName is sync.Manager's own state directory - index, upload
+// sessions, conflicts, and staged downloads. Its writes and renames are
+// never user changes, so the watcher filters it unconditionally rather
+// than relying on the user-configurable ignore list.
+const internalDirName = ".youlab-sync"
+
+func isInternalPath(relPath string) bool {
+	return relPath == internalDirName || strings.HasPrefix(relPath, internalDirName+string(filepath.Separator))
+}
+
 // Event represents a file change event
 type Event struct {
 	Path      string
@@ -46,12 +58,12 @@ func (o Operation) String() string {
 
 // Watcher watches a directory for file changes with debouncing
 type Watcher struct {
-	rootPath       string
-	ignorePatterns []string
-	debounce       time.Duration
-	events         chan Event
-	errors         chan error
-	fsWatcher      *fsnotify.Watcher
+	rootPath  string
+	matcher   *ignore.Matcher
+	debounce  time.Duration
+	events    chan Event
+	errors    chan error
+	fsWatcher *fsnotify.Watcher
 
 	mu            sync.Mutex
 	pendingEvents map[string]*pendingEvent
@@ -64,23 +76,25 @@ type pendingEvent struct {
 	timer *time.Timer
 }
 
-// New creates a new file watcher
-func New(rootPath string, ignorePatterns []string, debounce time.Duration) (*Watcher, error) {
+// New creates a new file watcher. matcher is shared with sync.Manager so
+// ignore behavior is identical across the initial walk, live events, and
+// remote diffing.
+func New(rootPath string, matcher *ignore.Matcher, debounce time.Duration) (*Watcher, error) {
 	fsWatcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
 
 	w := &Watcher{
-		rootPath:       rootPath,
-		ignorePatterns: ignorePatterns,
-		debounce:       debounce,
-		events:         make(chan Event, 100),
-		errors:         make(chan error, 10),
-		fsWatcher:      fsWatcher,
-		pendingEvents:  make(map[string]*pendingEvent),
-		stopCh:         make(chan struct{}),
-		doneCh:         make(chan struct{}),
+		rootPath:      rootPath,
+		matcher:       matcher,
+		debounce:      debounce,
+		events:        make(chan Event, 100),
+		errors:        make(chan error, 10),
+		fsWatcher:     fsWatcher,
+		pendingEvents: make(map[string]*pendingEvent),
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
 	}
 
 	return w, nil
@@ -162,9 +176,17 @@ func (w *Watcher) handleFSEvent(event fsnotify.Event) {
 		return
 	}
 
+	if isInternalPath(relPath) {
+		return
+	}
+
 	// Check if path should be ignored
-	if w.shouldIgnore(relPath) {
-		logrus.Debugf("Ignoring event for: %s", relPath)
+	isDir := false
+	if info, err := os.Stat(event.Name); err == nil {
+		isDir = info.IsDir()
+	}
+	if w.matcher.Match(relPath, isDir) {
+		logging.Debugf(logging.Watcher, "Ignoring event for: %s", relPath)
 		return
 	}
 
@@ -230,49 +252,40 @@ func (w *Watcher) addDirRecursive(root string) error {
 			return err
 		}
 
+		if !info.IsDir() {
+			return nil
+		}
+
 		// Get relative path for ignore check
 		relPath, err := filepath.Rel(w.rootPath, path)
 		if err != nil {
 			relPath = path
 		}
+		if relPath == "." {
+			relPath = ""
+		}
 
-		// Skip ignored directories
-		if info.IsDir() && w.shouldIgnore(relPath) {
+		if isInternalPath(relPath) {
 			return filepath.SkipDir
 		}
 
-		// Only watch directories
-		if info.IsDir() {
-			if err := w.fsWatcher.Add(path); err != nil {
-				logrus.Warnf("Failed to watch directory %s: %v", path, err)
-			} else {
-				logrus.Debugf("Watching directory: %s", path)
-			}
+		// Pick up any .youlabignore scoped to this directory before
+		// deciding whether to descend into it
+		if err := w.matcher.LoadDir(path, relPath); err != nil {
+			logrus.Warnf("Failed to load .youlabignore in %s: %v", path, err)
 		}
 
-		return nil
-	})
-}
-
-func (w *Watcher) shouldIgnore(path string) bool {
-	// Check each component of the path
-	parts := strings.Split(path, string(filepath.Separator))
-
-	for _, pattern := range w.ignorePatterns {
-		// Check if any path component matches the pattern
-		for _, part := range parts {
-			matched, err := filepath.Match(pattern, part)
-			if err == nil && matched {
-				return true
-			}
+		// Skip ignored directories
+		if relPath != "" && w.matcher.Match(relPath, true) {
+			return filepath.SkipDir
 		}
 
-		// Also check the full path
-		matched, err := filepath.Match(pattern, filepath.Base(path))
-		if err == nil && matched {
-			return true
+		if err := w.fsWatcher.Add(path); err != nil {
+			logrus.Warnf("Failed to watch directory %s: %v", path, err)
+		} else {
+			logging.Debugf(logging.Watcher, "Watching directory: %s", path)
 		}
-	}
 
-	return false
+		return nil
+	})
 }